@@ -0,0 +1,121 @@
+package opengauss
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// dsnURLSchemes are the URL schemes pq.ParseConfig recognizes as database
+// URLs rather than keyword=value DSNs (see its ParseURLToMap).
+var dsnURLSchemes = []string{"postgres://", "postgresql://", "opengauss://", "mogdb://"}
+
+func isURLDSN(dsn string) bool {
+	for _, scheme := range dsnURLSchemes {
+		if strings.HasPrefix(dsn, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// validSSLModes are the only sslmode values the underlying driver's TLS
+// handshake (see its ssl.go) understands.
+var validSSLModes = map[string]bool{
+	"disable":     true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// validEncryptionTypes are the password-stored methods openGauss supports.
+var validEncryptionTypes = map[string]bool{
+	"MD5":    true,
+	"SHA256": true,
+	"SM3":    true,
+}
+
+// validateAuthConfig rejects Config combinations the driver can't honor
+// before Initialize spends a round trip discovering that the hard way:
+// an unrecognized SSLMode/EncryptionType, or GSSEncMode and SSLMode both
+// demanding "require", which would mean requiring two mutually exclusive
+// transport-level encryption schemes on the same connection.
+func (dia Dialector) validateAuthConfig() error {
+	if dia.Config.SSLMode != "" && !validSSLModes[dia.Config.SSLMode] {
+		return fmt.Errorf("opengauss: unsupported SSLMode %q, want one of disable, require, verify-ca, verify-full", dia.Config.SSLMode)
+	}
+
+	if dia.Config.EncryptionType != "" && !validEncryptionTypes[strings.ToUpper(dia.Config.EncryptionType)] {
+		return fmt.Errorf("opengauss: unsupported EncryptionType %q, want MD5, SHA256 or SM3", dia.Config.EncryptionType)
+	}
+
+	if strings.EqualFold(dia.Config.GSSEncMode, "require") && strings.EqualFold(dia.Config.SSLMode, "require") {
+		return fmt.Errorf("opengauss: GSSEncMode and SSLMode cannot both be %q", "require")
+	}
+
+	return nil
+}
+
+// buildDSN merges the typed SSL/Kerberos Config fields into Config.DSN, so
+// pq.ParseConfig picks them up the same way it would if they'd been
+// hand-written into the DSN string. It handles both DSN forms pq.ParseConfig
+// accepts: keyword=value (params are appended as more keyword=value pairs)
+// and database URLs such as postgres://host/db?sslmode=... (params are
+// merged into the existing query string instead, since appending
+// space-separated keyword=value pairs after a URL produces an invalid DSN).
+func (dia Dialector) buildDSN() (string, error) {
+	var params [][2]string
+	add := func(keyword, value string) {
+		if value != "" {
+			params = append(params, [2]string{keyword, value})
+		}
+	}
+
+	add("sslmode", dia.Config.SSLMode)
+	add("sslrootcert", dia.Config.SSLRootCert)
+	add("sslcert", dia.Config.SSLCert)
+	add("sslkey", dia.Config.SSLKey)
+	add("krbsrvname", dia.Config.KerberosServiceName)
+
+	if len(params) == 0 {
+		return dia.Config.DSN, nil
+	}
+
+	if isURLDSN(dia.Config.DSN) {
+		return mergeURLParams(dia.Config.DSN, params)
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, kv := range params {
+		parts = append(parts, kv[0]+"="+quoteDSNValue(kv[1]))
+	}
+	return strings.TrimSpace(dia.Config.DSN + " " + strings.Join(parts, " ")), nil
+}
+
+// mergeURLParams sets params as query parameters on the database URL dsn,
+// overriding any same-named parameter already present in it.
+func mergeURLParams(dsn string, params [][2]string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("opengauss: parsing DSN as URL: %w", err)
+	}
+
+	q := u.Query()
+	for _, kv := range params {
+		q.Set(kv[0], kv[1])
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// quoteDSNValue wraps a libpq DSN parameter value in single quotes, escaping
+// backslashes and quotes, if it contains characters that would otherwise
+// break keyword=value parsing.
+func quoteDSNValue(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -0,0 +1,125 @@
+package opengauss
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+func newTestMigrator(dia Dialector) Migrator {
+	return Migrator{migrator.Migrator{Config: migrator.Config{
+		DB:        &gorm.DB{Config: &gorm.Config{Dialector: dia}},
+		Dialector: dia,
+	}}}
+}
+
+func TestDistributeByClauseValid(t *testing.T) {
+	m := newTestMigrator(Dialector{Config: &Config{}})
+
+	cases := map[string]string{
+		"hash(id)":    `HASH("id")`,
+		"HASH( id )":  `HASH("id")`,
+		"replication": "REPLICATION",
+		"ROUNDROBIN":  "ROUNDROBIN",
+	}
+	for in, want := range cases {
+		got, err := m.distributeByClause(in)
+		if err != nil {
+			t.Errorf("distributeByClause(%q) returned error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("distributeByClause(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDistributeByClauseRejectsInvalidInput(t *testing.T) {
+	m := newTestMigrator(Dialector{Config: &Config{}})
+
+	invalid := []string{
+		"",
+		"hash(id); DROP TABLE users; --",
+		"bogus",
+		"hash(id) extra",
+	}
+	for _, in := range invalid {
+		if _, err := m.distributeByClause(in); err == nil {
+			t.Errorf("distributeByClause(%q) = nil error, want rejection", in)
+		}
+	}
+}
+
+func TestDistributeByClauseQuotesColumn(t *testing.T) {
+	m := newTestMigrator(Dialector{Config: &Config{}})
+
+	got, err := m.distributeByClause(`hash(id); DROP TABLE users`)
+	if err == nil {
+		t.Fatalf("distributeByClause with embedded SQL = %q, want rejection", got)
+	}
+
+	got, err = m.distributeByClause("hash(id)")
+	if err != nil {
+		t.Fatalf("distributeByClause(hash(id)) returned error: %v", err)
+	}
+	if !strings.Contains(got, `"id"`) {
+		t.Fatalf("distributeByClause(hash(id)) = %q, want the column quoted", got)
+	}
+}
+
+func parseSchema(t *testing.T, dest interface{}) *schema.Schema {
+	t.Helper()
+	s, err := schema.Parse(dest, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("schema.Parse() error: %v", err)
+	}
+	return s
+}
+
+func TestDistributionTagOfQuotesAndValidates(t *testing.T) {
+	m := newTestMigrator(Dialector{Config: &Config{}})
+
+	type hashTagged struct {
+		ID int `gorm:"distributeBy:hash(id);nodegroup:group1"`
+	}
+	distributeBy, nodeGroup, err := m.distributionTagOf(parseSchema(t, &hashTagged{}))
+	if err != nil {
+		t.Fatalf("distributionTagOf() returned error: %v", err)
+	}
+	if distributeBy != `HASH("id")` {
+		t.Fatalf("distributionTagOf() distributeBy = %q, want quoted HASH(\"id\")", distributeBy)
+	}
+	if nodeGroup != "group1" {
+		t.Fatalf("distributionTagOf() nodeGroup = %q, want group1", nodeGroup)
+	}
+}
+
+func TestDistributionTagOfRejectsInvalidTag(t *testing.T) {
+	m := newTestMigrator(Dialector{Config: &Config{}})
+
+	type badTagged struct {
+		ID int `gorm:"distributeBy:bogus"`
+	}
+	if _, _, err := m.distributionTagOf(parseSchema(t, &badTagged{})); err == nil {
+		t.Fatal("distributionTagOf() with a malformed distributeBy tag = nil error, want rejection")
+	}
+}
+
+func TestDistributionTagOfNoTags(t *testing.T) {
+	m := newTestMigrator(Dialector{Config: &Config{}})
+
+	type untagged struct {
+		ID int
+	}
+	distributeBy, nodeGroup, err := m.distributionTagOf(parseSchema(t, &untagged{}))
+	if err != nil {
+		t.Fatalf("distributionTagOf() returned error: %v", err)
+	}
+	if distributeBy != "" || nodeGroup != "" {
+		t.Fatalf("distributionTagOf() = (%q, %q), want both empty for an untagged model", distributeBy, nodeGroup)
+	}
+}
@@ -0,0 +1,191 @@
+package opengauss
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+const redacted = "***"
+
+// redactionFailedSQL is returned by Explain in place of the real statement
+// when RedactParams names a column that appears in the SQL text but that
+// redactedIndexesOf couldn't confidently map to a bind-variable position
+// (e.g. its value is wrapped in an expression like `md5($1)`). Audit-log
+// redaction must fail closed: logging the statement unredacted because a
+// regex didn't match would defeat the whole feature.
+const redactionFailedSQL = "-- SQL omitted: could not confirm every RedactParams column was redacted --"
+
+// insertColumnsMatcher finds the column list and first VALUES tuple of an
+// INSERT statement, e.g. `("password","name") VALUES ($1,$2)`.
+var insertColumnsMatcher = regexp.MustCompile(`(?i)\(([^()]+)\)\s*VALUES\s*(\([^()]+\)(?:\s*,\s*\([^()]+\))*)`)
+
+// insertTupleMatcher pulls out each individual `($1,$2)` row out of the
+// VALUES clause matched above, so batched inserts redact every row.
+var insertTupleMatcher = regexp.MustCompile(`\(([^()]+)\)`)
+
+// assignmentMatcher finds `"col" = $N` / `col=$N` assignments, as produced by
+// UPDATE ... SET and WHERE clauses.
+var assignmentMatcher = regexp.MustCompile(`(?i)"?(\w+)"?\s*=\s*\$(\d+)`)
+
+// redactVars returns vars with any value that RedactParams/RedactPattern
+// marks sensitive replaced by the literal string "***", leaving vars
+// untouched (no copy) when neither option is set. sqlText is only used to
+// locate which $N placeholder a RedactParams column name maps to; it is
+// never itself modified. ok is false when a RedactParams column couldn't be
+// confidently resolved to a placeholder, meaning redaction may be
+// incomplete; callers must treat that as a failure to redact, not log vars
+// as returned.
+func (dia Dialector) redactVars(sqlText string, vars []interface{}) (out []interface{}, ok bool) {
+	if len(dia.RedactParams) == 0 && dia.RedactPattern == nil {
+		return vars, true
+	}
+
+	indexes, ok := dia.redactedIndexesOf(sqlText)
+	if !ok {
+		return nil, false
+	}
+
+	out = append(make([]interface{}, 0, len(vars)), vars...)
+	for _, idx := range indexes {
+		if idx >= 0 && idx < len(out) {
+			out[idx] = redacted
+		}
+	}
+
+	if dia.RedactPattern != nil {
+		for i, v := range out {
+			if v == redacted {
+				continue
+			}
+			if dia.RedactPattern.MatchString(fmt.Sprint(v)) {
+				out[i] = redacted
+			}
+		}
+	}
+
+	return out, true
+}
+
+// redactedIndexesOf returns the zero-based var positions that RedactParams
+// resolves to by scanning sqlText for the column names it lists, both in an
+// INSERT's column/VALUES lists and in `col = $N` assignments. ok is false
+// when some RedactParams column is mentioned in sqlText (so it might carry a
+// sensitive value) but neither matcher could pin down which placeholder it
+// maps to — e.g. a VALUES tuple with a nested expression like `md5($1)`,
+// which insertTupleMatcher's no-nested-parens pattern can't parse. Callers
+// must treat that as "redaction can't be confirmed", not "nothing to redact".
+func (dia Dialector) redactedIndexesOf(sqlText string) (indexes []int, ok bool) {
+	if len(dia.RedactParams) == 0 {
+		return nil, true
+	}
+
+	resolved := make(map[string]bool, len(dia.RedactParams))
+
+	if m := insertColumnsMatcher.FindStringSubmatch(sqlText); m != nil {
+		columns := splitAndTrim(m[1])
+		for _, tuple := range insertTupleMatcher.FindAllStringSubmatch(m[2], -1) {
+			values := splitAndTrim(tuple[1])
+			for i, column := range columns {
+				if i >= len(values) || !dia.isRedactedParam(column) {
+					continue
+				}
+				resolved[strings.ToLower(column)] = true
+				if n, ok := placeholderIndex(values[i]); ok {
+					indexes = append(indexes, n)
+				}
+			}
+		}
+	}
+
+	for _, m := range assignmentMatcher.FindAllStringSubmatch(sqlText, -1) {
+		if !dia.isRedactedParam(m[1]) {
+			continue
+		}
+		resolved[strings.ToLower(m[1])] = true
+		if n, err := strconv.Atoi(m[2]); err == nil {
+			indexes = append(indexes, n-1)
+		}
+	}
+
+	for _, name := range dia.RedactParams {
+		if resolved[strings.ToLower(name)] {
+			continue
+		}
+		if columnMentioned(sqlText, name) {
+			return nil, false
+		}
+	}
+
+	return indexes, true
+}
+
+// columnMentioned reports whether name appears as a bare or quoted
+// identifier anywhere in sqlText, used to detect a RedactParams column that
+// redactedIndexesOf saw in the statement but couldn't resolve to a
+// placeholder.
+func columnMentioned(sqlText, name string) bool {
+	matched, _ := regexp.MatchString(`(?i)"?\b`+regexp.QuoteMeta(name)+`\b"?`, sqlText)
+	return matched
+}
+
+func (dia Dialector) isRedactedParam(column string) bool {
+	for _, name := range dia.RedactParams {
+		if strings.EqualFold(name, column) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitAndTrim(list string) []string {
+	parts := strings.Split(list, ",")
+	for i, p := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(p), `"`)
+	}
+	return parts
+}
+
+func placeholderIndex(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "$") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value[1:])
+	if err != nil {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+// SamplingLogger wraps a logger.Interface and bounds the cost of logging
+// slow queries: once a query's elapsed time reaches SlowThreshold, only a
+// SlowSampleRate fraction of them are fully expanded through Explain (paying
+// its ExplainSQL/redaction cost); the rest are logged with their SQL text
+// replaced by a placeholder, keeping the rows-affected/err reporting of the
+// wrapped logger intact. Queries faster than SlowThreshold always expand
+// normally, and the wrapper is a no-op when SlowThreshold is zero.
+//
+// Useful in openGauss deployments where SQL audit logging is itself
+// regulated and must not become a source of unbounded CPU cost under load.
+type SamplingLogger struct {
+	logger.Interface
+	SlowThreshold  time.Duration
+	SlowSampleRate float64
+}
+
+func (l SamplingLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if err == nil && l.SlowThreshold > 0 && time.Since(begin) >= l.SlowThreshold && rand.Float64() >= l.SlowSampleRate {
+		l.Interface.Trace(ctx, begin, func() (string, int64) {
+			return "-- SQL omitted: slow query sampled out under SlowSampleRate --", -1
+		}, err)
+		return
+	}
+	l.Interface.Trace(ctx, begin, fc, err)
+}
@@ -0,0 +1,66 @@
+package opengauss
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func mustCompileCardPattern(t *testing.T) *regexp.Regexp {
+	t.Helper()
+	return regexp.MustCompile(`^\d{16}$`)
+}
+
+func TestExplainRedactsSimpleInsert(t *testing.T) {
+	dia := Dialector{Config: &Config{RedactParams: []string{"password"}}}
+
+	got := dia.Explain(`INSERT INTO "users" ("password","name") VALUES ($1,$2)`, "hunter2", "alice")
+	if strings.Contains(got, "hunter2") {
+		t.Fatalf("Explain() = %q, want password redacted", got)
+	}
+	if !strings.Contains(got, "alice") {
+		t.Fatalf("Explain() = %q, want the non-redacted column to still be expanded", got)
+	}
+}
+
+func TestExplainRedactsAssignment(t *testing.T) {
+	dia := Dialector{Config: &Config{RedactParams: []string{"token"}}}
+
+	got := dia.Explain(`UPDATE "sessions" SET "token" = $1 WHERE "id" = $2`, "secret-token", 42)
+	if strings.Contains(got, "secret-token") {
+		t.Fatalf("Explain() = %q, want token redacted", got)
+	}
+}
+
+func TestExplainFailsClosedOnUnparseableInsert(t *testing.T) {
+	dia := Dialector{Config: &Config{RedactParams: []string{"password"}}}
+
+	// The nested md5(...) expression breaks the no-nested-parens VALUES
+	// tuple matcher, so the password value can't be resolved to a $N
+	// position. Explain must not fall through to logging it in clear text.
+	got := dia.Explain(`INSERT INTO "users" ("password","name") VALUES (md5($1),$2)`, "hunter2", "alice")
+	if strings.Contains(got, "hunter2") {
+		t.Fatalf("Explain() = %q, want the unparseable statement omitted instead of leaking the value", got)
+	}
+	if got != redactionFailedSQL {
+		t.Fatalf("Explain() = %q, want the redaction-failed placeholder %q", got, redactionFailedSQL)
+	}
+}
+
+func TestExplainUnrelatedColumnsUnaffected(t *testing.T) {
+	dia := Dialector{Config: &Config{RedactParams: []string{"password"}}}
+
+	got := dia.Explain(`SELECT "id","name" FROM "users" WHERE "id" = $1`, 1)
+	if strings.Contains(got, redactionFailedSQL) {
+		t.Fatalf("Explain() = %q, want a normal expansion since no redacted column is present", got)
+	}
+}
+
+func TestExplainPatternRedactsRegardlessOfColumn(t *testing.T) {
+	dia := Dialector{Config: &Config{RedactPattern: mustCompileCardPattern(t)}}
+
+	got := dia.Explain(`UPDATE "accounts" SET "note" = $1 WHERE "id" = $2`, "4111111111111111", 1)
+	if strings.Contains(got, "4111111111111111") {
+		t.Fatalf("Explain() = %q, want the card-shaped value redacted", got)
+	}
+}
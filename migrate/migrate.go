@@ -0,0 +1,319 @@
+// Package migrate layers a versioned, ordered migration runner on top of
+// gorm.Migrator. It mirrors the shape of xormigrate/gormigrate but keeps
+// concurrent nodes from racing each other by taking a session-level
+// pg_advisory_lock around the bookkeeping table, which is something the
+// upstream gorm migrator doesn't provide.
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockedSession pins the *gorm.DB session used for withLock's
+// lock/migrate/unlock sequence to a single physical connection, obtained
+// via db.DB().Conn. Advisory locks are session-scoped, so the lock acquire,
+// the migration transaction and the deferred unlock must all run on the
+// same connection or the lock provides no mutual exclusion. The returned
+// close func must be deferred by the caller to release the connection back
+// to the pool.
+func lockedSession(db *gorm.DB) (tx *gorm.DB, closeConn func() error, err error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx := db.Statement.Context
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx = db.Session(&gorm.Session{Context: ctx})
+	tx.Statement.ConnPool = conn
+	return tx, conn.Close, nil
+}
+
+// DefaultTableName is the bookkeeping table created to track applied steps.
+const DefaultTableName = "schema_migrations"
+
+// Step is a single versioned migration. ID must be unique and steps are
+// applied in the order they're registered with New, not sorted by ID.
+type Step struct {
+	// ID identifies the step, e.g. a timestamp such as "20240102150405" or a
+	// simple incrementing number formatted as a string.
+	ID string
+	// Up applies the migration. It receives a *gorm.DB bound to the running
+	// transaction, unless DisableTx is set.
+	Up func(tx *gorm.DB) error
+	// Down reverts the migration. May be nil if the step is not reversible,
+	// in which case Rollback/RollbackTo fail with ErrRollbackImpossible.
+	Down func(tx *gorm.DB) error
+	// DisableTx runs the step outside of a transaction. openGauss, like
+	// PostgreSQL, cannot run some DDL (e.g. CREATE INDEX CONCURRENTLY) inside
+	// a transaction block, so such steps must opt out individually.
+	DisableTx bool
+}
+
+var (
+	// ErrNoStepDefined is returned when Migrator has no steps registered.
+	ErrNoStepDefined = errors.New("migrate: no migration step defined")
+	// ErrStepIDDoesNotExist is returned when MigrateTo/RollbackTo targets an
+	// unknown step ID.
+	ErrStepIDDoesNotExist = errors.New("migrate: step ID does not exist")
+	// ErrRollbackImpossible is returned when a step without a Down func is
+	// rolled back.
+	ErrRollbackImpossible = errors.New("migrate: step has no Down func, cannot roll back")
+	// ErrDuplicateStepID is returned when two registered steps share an ID.
+	ErrDuplicateStepID = errors.New("migrate: duplicate step ID")
+)
+
+// record is the row shape of the bookkeeping table.
+type record struct {
+	ID        string `gorm:"primaryKey;column:id;size:255"`
+	AppliedAt time.Time
+}
+
+func (record) TableName() string { return DefaultTableName }
+
+// Status describes whether a registered step has been applied.
+type Status struct {
+	ID        string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies/rolls back an ordered list of Steps against a *gorm.DB.
+type Migrator struct {
+	db        *gorm.DB
+	steps     []Step
+	tableName string
+}
+
+// New returns a Migrator bound to db with the given steps, applied in the
+// order passed in. Typically obtained via opengauss.Migrator.Migrations.
+func New(db *gorm.DB, steps ...Step) *Migrator {
+	return &Migrator{db: db, steps: steps, tableName: DefaultTableName}
+}
+
+// TableName overrides the bookkeeping table name (default schema_migrations).
+func (m *Migrator) TableName(name string) *Migrator {
+	m.tableName = name
+	return m
+}
+
+// MigrateTo applies all pending steps up to and including id.
+func (m *Migrator) MigrateTo(id string) error {
+	if err := m.checkStepIDExists(id); err != nil {
+		return err
+	}
+	return m.withLock(func(tx *gorm.DB) error {
+		for _, step := range m.steps {
+			applied, err := m.stepApplied(tx, step.ID)
+			if err != nil {
+				return err
+			}
+			if !applied {
+				if err := m.applyStep(tx, step); err != nil {
+					return fmt.Errorf("migrate: step %q: %w", step.ID, err)
+				}
+			}
+			if step.ID == id {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Rollback undoes the last n applied steps, most recent first.
+func (m *Migrator) Rollback(n int) error {
+	if len(m.steps) == 0 {
+		return ErrNoStepDefined
+	}
+	return m.withLock(func(tx *gorm.DB) error {
+		for i := len(m.steps) - 1; i >= 0 && n > 0; i-- {
+			step := m.steps[i]
+			applied, err := m.stepApplied(tx, step.ID)
+			if err != nil {
+				return err
+			}
+			if !applied {
+				continue
+			}
+			if err := m.revertStep(tx, step); err != nil {
+				return fmt.Errorf("migrate: step %q: %w", step.ID, err)
+			}
+			n--
+		}
+		return nil
+	})
+}
+
+// RollbackTo undoes applied steps down to, but not including, id.
+func (m *Migrator) RollbackTo(id string) error {
+	if err := m.checkStepIDExists(id); err != nil {
+		return err
+	}
+	return m.withLock(func(tx *gorm.DB) error {
+		for i := len(m.steps) - 1; i >= 0; i-- {
+			step := m.steps[i]
+			if step.ID == id {
+				break
+			}
+			applied, err := m.stepApplied(tx, step.ID)
+			if err != nil {
+				return err
+			}
+			if !applied {
+				continue
+			}
+			if err := m.revertStep(tx, step); err != nil {
+				return fmt.Errorf("migrate: step %q: %w", step.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Status reports, for every registered step in order, whether it has been
+// applied and when.
+func (m *Migrator) Status() ([]Status, error) {
+	if err := m.createTableIfNotExists(m.db); err != nil {
+		return nil, err
+	}
+
+	var rows []record
+	if err := m.db.Table(m.tableName).Order("applied_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[string]time.Time, len(rows))
+	for _, r := range rows {
+		appliedAt[r.ID] = r.AppliedAt
+	}
+
+	statuses := make([]Status, 0, len(m.steps))
+	for _, step := range m.steps {
+		at, applied := appliedAt[step.ID]
+		statuses = append(statuses, Status{ID: step.ID, Applied: applied, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) applyStep(tx *gorm.DB, step Step) error {
+	if step.Up == nil {
+		return nil
+	}
+	run := func(db *gorm.DB) error {
+		if err := step.Up(db); err != nil {
+			return err
+		}
+		return db.Table(m.tableName).Create(&record{ID: step.ID, AppliedAt: time.Now()}).Error
+	}
+	if step.DisableTx {
+		return run(tx.Session(&gorm.Session{NewDB: true}))
+	}
+	return run(tx)
+}
+
+func (m *Migrator) revertStep(tx *gorm.DB, step Step) error {
+	if step.Down == nil {
+		return ErrRollbackImpossible
+	}
+	run := func(db *gorm.DB) error {
+		if err := step.Down(db); err != nil {
+			return err
+		}
+		return db.Table(m.tableName).Where("id = ?", step.ID).Delete(&record{}).Error
+	}
+	if step.DisableTx {
+		return run(tx.Session(&gorm.Session{NewDB: true}))
+	}
+	return run(tx)
+}
+
+func (m *Migrator) stepApplied(tx *gorm.DB, id string) (bool, error) {
+	var count int64
+	err := tx.Table(m.tableName).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (m *Migrator) checkStepIDExists(id string) error {
+	for _, step := range m.steps {
+		if step.ID == id {
+			return nil
+		}
+	}
+	return ErrStepIDDoesNotExist
+}
+
+func (m *Migrator) createTableIfNotExists(db *gorm.DB) error {
+	if db.Migrator().HasTable(m.tableName) {
+		return nil
+	}
+	return db.Table(m.tableName).AutoMigrate(&record{})
+}
+
+// withLock serialises fn against every other node running migrations against
+// the same table by holding a session-level pg_advisory_lock for the
+// duration of fn, so two nodes booting at the same time don't both try to
+// apply the same step. pg_advisory_lock/pg_advisory_unlock are scoped to the
+// connection that issued them, so the lock acquire, fn's transaction and the
+// deferred unlock all run pinned to one *sql.Conn via lockedSession, instead
+// of each independently borrowing a connection from m.db's pool.
+func (m *Migrator) withLock(fn func(tx *gorm.DB) error) error {
+	if len(m.steps) == 0 {
+		return ErrNoStepDefined
+	}
+	if ids := duplicateStepID(m.steps); ids != "" {
+		return fmt.Errorf("%w: %s", ErrDuplicateStepID, ids)
+	}
+
+	tx, closeConn, err := lockedSession(m.db)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	key := lockKey(m.tableName)
+	if err := tx.Exec("SELECT pg_advisory_lock(?)", key).Error; err != nil {
+		return err
+	}
+	defer tx.Exec("SELECT pg_advisory_unlock(?)", key)
+
+	if err := m.createTableIfNotExists(tx); err != nil {
+		return err
+	}
+
+	return tx.Transaction(fn)
+}
+
+func duplicateStepID(steps []Step) string {
+	seen := make(map[string]struct{}, len(steps))
+	ids := make([]string, 0)
+	for _, step := range steps {
+		if _, ok := seen[step.ID]; ok {
+			ids = append(ids, step.ID)
+			continue
+		}
+		seen[step.ID] = struct{}{}
+	}
+	sort.Strings(ids)
+	if len(ids) == 0 {
+		return ""
+	}
+	return fmt.Sprint(ids)
+}
+
+// lockKey derives a stable advisory lock key from the bookkeeping table name
+// so migrators guarding different tables don't contend with each other.
+func lockKey(tableName string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("opengauss/migrate:" + tableName))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,199 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// fakeDriver/fakeConn is a minimal database/sql driver whose only purpose is
+// to record which connection instance every statement ran against, so
+// withLock's connection-pinning can be verified without a live database.
+type fakeDriver struct {
+	mu    sync.Mutex
+	conns int
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	d.conns++
+	id := d.conns
+	d.mu.Unlock()
+	return &fakeConn{id: id}, nil
+}
+
+var fakeDriverOnce sync.Once
+var theFakeDriver = &fakeDriver{}
+
+func registerFakeDriver() {
+	fakeDriverOnce.Do(func() {
+		sql.Register("migrate-fake", theFakeDriver)
+	})
+}
+
+// connIDs records the connection ID every Exec/Query ran against, in order.
+type connIDs struct {
+	mu  sync.Mutex
+	ids []int
+}
+
+func (r *connIDs) record(id int) {
+	r.mu.Lock()
+	r.ids = append(r.ids, id)
+	r.mu.Unlock()
+}
+
+var recorder *connIDs
+
+type fakeConn struct {
+	id int
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{conn: c}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	recorder.record(c.id)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	recorder.record(c.id)
+	return &fakeRows{}, nil
+}
+
+type fakeStmt struct{ conn *fakeConn }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	recorder.record(s.conn.id)
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	recorder.record(s.conn.id)
+	return &fakeRows{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// fakeRows yields a single row with a single zero-valued column, enough for
+// the count(*)/CURRENT_DATABASE()-style scalar queries the base gorm
+// migrator issues from HasTable/CurrentDatabase.
+type fakeRows struct {
+	done bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(0)
+	return nil
+}
+
+// fakeDialector is just enough of a gorm.Dialector to drive the base
+// gorm.io/gorm/migrator.Migrator through CreateTable/HasTable against
+// fakeConn, without depending on this package's parent (which would be an
+// import cycle, since it imports migrate).
+type fakeDialector struct {
+	sqlDB *sql.DB
+}
+
+func (d fakeDialector) Name() string { return "fake" }
+func (d fakeDialector) Initialize(db *gorm.DB) error {
+	db.ConnPool = d.sqlDB
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	return nil
+}
+func (fakeDialector) DataTypeOf(*schema.Field) string { return "TEXT" }
+func (fakeDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{SQL: "NULL"}
+}
+func (fakeDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte('?')
+}
+func (fakeDialector) QuoteTo(writer clause.Writer, s string) {
+	writer.WriteByte('"')
+	writer.WriteString(s)
+	writer.WriteByte('"')
+}
+func (fakeDialector) Explain(sql string, vars ...interface{}) string { return sql }
+func (fakeDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return migrator.Migrator{Config: migrator.Config{DB: db, Dialector: fakeDialector{}}}
+}
+
+func newFakeDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	registerFakeDriver()
+	recorder = &connIDs{}
+
+	sqlDB, err := sql.Open("migrate-fake", "fake")
+	if err != nil {
+		t.Fatalf("sql.Open() error: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	gdb, err := gorm.Open(fakeDialector{sqlDB: sqlDB}, &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open() error: %v", err)
+	}
+	return gdb
+}
+
+func TestWithLockPinsSingleConnection(t *testing.T) {
+	gdb := newFakeDB(t)
+	m := New(gdb, Step{ID: "1", Up: func(tx *gorm.DB) error { return nil }})
+
+	var fnConnIDs []int
+	err := m.withLock(func(tx *gorm.DB) error {
+		// fn runs inside tx.Transaction, so ConnPool here is the *sql.Tx
+		// that was begun against the pinned *sql.Conn, not the conn itself;
+		// the recorder below confirms the underlying connection is still
+		// the same one used for the lock and will be used for the unlock.
+		before := len(recorder.ids)
+		if err := tx.Exec("SELECT 1").Error; err != nil {
+			return err
+		}
+		fnConnIDs = append(fnConnIDs, recorder.ids[before:]...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withLock() error: %v", err)
+	}
+
+	if len(recorder.ids) == 0 {
+		t.Fatal("no queries were recorded, test isn't exercising withLock")
+	}
+	want := recorder.ids[0]
+	for i, id := range recorder.ids {
+		if id != want {
+			t.Fatalf("query %d ran on connection %d, want every statement in withLock pinned to connection %d (sequence: %v)", i, id, want, recorder.ids)
+		}
+	}
+	for _, id := range fnConnIDs {
+		if id != want {
+			t.Fatalf("fn's query ran on connection %d, want %d", id, want)
+		}
+	}
+}
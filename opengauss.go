@@ -1,11 +1,14 @@
 package opengauss
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"gitee.com/opengauss/openGauss-connector-go-pq"
 	"gorm.io/gorm"
@@ -27,11 +30,93 @@ type Config struct {
 	PreferSimpleProtocol bool
 	WithoutReturning     bool
 	Conn                 gorm.ConnPool
+
+	// SkipInitializeWithVersion skips probing the server with `SELECT version()`
+	// during Initialize. Leave it unset to let the Dialector auto-detect whether
+	// it is talking to real openGauss or a PostgreSQL-compatible server and pick
+	// the appropriate SQL dialect (e.g. ON DUPLICATE KEY UPDATE vs ON CONFLICT
+	// DO UPDATE) accordingly.
+	SkipInitializeWithVersion bool
+
+	// ServerVersion holds the raw response of `SELECT version()`, populated during
+	// Initialize unless SkipInitializeWithVersion is set.
+	ServerVersion string
+	// IsOpenGauss reports whether ServerVersion identifies an openGauss server, as
+	// opposed to a plain/compatible PostgreSQL server.
+	IsOpenGauss bool
+	// ServerMajorVersion and ServerMinorVersion hold the parsed openGauss/PostgreSQL
+	// version number, e.g. 5, 0 for "openGauss 5.0.0".
+	ServerMajorVersion int
+	ServerMinorVersion int
+
+	// UseMerge makes the "ON CONFLICT" clause builder emit a full
+	// `MERGE INTO ... USING ... WHEN MATCHED/WHEN NOT MATCHED` statement instead
+	// of `ON DUPLICATE KEY UPDATE`. Unlike ON DUPLICATE KEY UPDATE, MERGE can
+	// update columns that are part of the conflict target and supports
+	// composite conflict targets.
+	UseMerge bool
+
+	// Logger, when set, replaces db.Logger during Initialize. Use it to plug in
+	// a SamplingLogger so slow-query logging can't become a hot-path cost.
+	Logger logger.Interface
+
+	// RedactParams lists column names (matched case-insensitively against the
+	// SQL text) whose values Explain replaces with '***' instead of
+	// interpolating them, e.g. []string{"password", "token"}. RedactPattern
+	// does the same for any value whose string form matches the pattern,
+	// regardless of column, e.g. a credit-card-shaped regexp. Both apply to
+	// every statement explained through this Dialector, which is useful in
+	// openGauss deployments where SQL audit logs are themselves regulated.
+	RedactParams  []string
+	RedactPattern *regexp.Regexp
+
+	// SSLMode, SSLRootCert, SSLCert and SSLKey configure TLS the same way the
+	// DSN keywords sslmode/sslrootcert/sslcert/sslkey would, without callers
+	// having to hand-assemble that part of the DSN. SSLMode must be one of
+	// "disable", "require", "verify-ca" or "verify-full", the only modes the
+	// underlying driver's TLS handshake understands.
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	// EncryptionType documents which password-stored method the server is
+	// expected to challenge with: "MD5", "SHA256" or "SM3" (openGauss's GM
+	// cipher suite, backed by github.com/tjfoc/gmsm). The driver always
+	// negotiates the actual method from the server's auth request, so this
+	// field is validated rather than wired into the connection, letting
+	// misconfigured deployments fail fast instead of silently falling back to
+	// a weaker method.
+	EncryptionType string
+
+	// KerberosServiceName sets the DSN krbsrvname keyword used to build the
+	// GSSAPI service principal name for Kerberos authentication.
+	KerberosServiceName string
+	// GSSEncMode is validated against SSLMode (see Initialize) but otherwise
+	// unused: the underlying driver negotiates GSSAPI encryption itself and
+	// exposes no DSN keyword to configure it.
+	GSSEncMode string
+
+	// Hosts, when set, makes Initialize build a read/write-splitting
+	// ConnPool over a primary/standby cluster instead of opening a single
+	// connection to Config.DSN: writes and RETURNING-bearing statements go
+	// to the Primary-role host, plain reads are spread across Standby-role
+	// hosts by weighted round robin. Config.DSN still supplies everything
+	// but the host/port (TLS, auth, run-time params) for every node.
+	Hosts []HostConfig
+	// HealthCheckInterval controls how often each host in Hosts is
+	// reconfirmed as primary or standby via SELECT pg_is_in_recovery().
+	// Defaults to 30s. Only meaningful when Hosts is set.
+	HealthCheckInterval time.Duration
 }
 
 var (
 	timeZoneMatcher         = regexp.MustCompile("(time_zone|TimeZone)=(.*?)($|&| )")
 	defaultIdentifierLength = 63 // maximum identifier length for postgres
+
+	// serverVersionMatcher extracts the product name and major.minor version out of
+	// the `SELECT version()` response, e.g. "openGauss 5.0.0" or "PostgreSQL 9.2.4".
+	serverVersionMatcher = regexp.MustCompile(`(openGauss|PostgreSQL)\s+(\d+)\.(\d+)`)
 )
 
 func Open(dsn string) gorm.Dialector {
@@ -70,25 +155,25 @@ func (dia Dialector) Apply(config *gorm.Config) error {
 }
 
 func (dia Dialector) Initialize(db *gorm.DB) (err error) {
-	callbackConfig := &callbacks.Config{
-		CreateClauses: []string{"INSERT", "VALUES", "ON CONFLICT"},
-		UpdateClauses: []string{"UPDATE", "SET", "FROM", "WHERE"},
-		DeleteClauses: []string{"DELETE", "FROM", "WHERE"},
-	}
-	// register callbacks
-	if !dia.WithoutReturning {
-		callbackConfig.CreateClauses = append(callbackConfig.CreateClauses, "RETURNING")
-		callbackConfig.UpdateClauses = append(callbackConfig.UpdateClauses, "RETURNING")
-		callbackConfig.DeleteClauses = append(callbackConfig.DeleteClauses, "RETURNING")
-	}
-	callbacks.RegisterDefaultCallbacks(db, callbackConfig)
-
-	if dia.Conn != nil {
+	if len(dia.Config.Hosts) > 0 {
+		db.ConnPool, err = dia.newResolverConnPool()
+	} else if dia.Conn != nil {
 		db.ConnPool = dia.Conn
 	} else if dia.DriverName != "" {
 		db.ConnPool, err = sql.Open(dia.DriverName, dia.Config.DSN)
 	} else {
-		config, err := pq.ParseConfig(dia.Config.DSN)
+		if err = dia.validateAuthConfig(); err != nil {
+			return
+		}
+
+		var dsn string
+		dsn, err = dia.buildDSN()
+		if err != nil {
+			return
+		}
+
+		var config *pq.Config
+		config, err = pq.ParseConfig(dsn)
 		if err != nil {
 			return
 		}
@@ -100,6 +185,33 @@ func (dia Dialector) Initialize(db *gorm.DB) (err error) {
 		connector, _ := pq.NewConnectorConfig(config)
 		db.ConnPool = sql.OpenDB(connector)
 	}
+	if err != nil {
+		return
+	}
+
+	if !dia.Config.SkipInitializeWithVersion {
+		if verr := dia.detectServerVersion(db); verr != nil {
+			return verr
+		}
+	}
+
+	if dia.Config.Logger != nil {
+		db.Logger = dia.Config.Logger
+	}
+
+	callbackConfig := &callbacks.Config{
+		CreateClauses: []string{"INSERT", "VALUES", "ON CONFLICT"},
+		UpdateClauses: []string{"UPDATE", "SET", "FROM", "WHERE"},
+		DeleteClauses: []string{"DELETE", "FROM", "WHERE"},
+	}
+	// register callbacks
+	if dia.supportsReturning() {
+		callbackConfig.CreateClauses = append(callbackConfig.CreateClauses, "RETURNING")
+		callbackConfig.UpdateClauses = append(callbackConfig.UpdateClauses, "RETURNING")
+		callbackConfig.DeleteClauses = append(callbackConfig.DeleteClauses, "RETURNING")
+	}
+	callbacks.RegisterDefaultCallbacks(db, callbackConfig)
+
 	for k, v := range dia.ClauseBuilders() {
 		db.ClauseBuilders[k] = v
 	}
@@ -107,6 +219,43 @@ func (dia Dialector) Initialize(db *gorm.DB) (err error) {
 	return
 }
 
+// detectServerVersion probes the server with `SELECT version()` and stores the
+// parsed product name and major/minor version on the Config, the same way the
+// MySQL driver bootstraps itself from `SELECT VERSION()`. ClauseBuilders and
+// supportsReturning branch on IsOpenGauss/ServerMajorVersion/ServerMinorVersion
+// instead of a single coarse capability flag; DataTypeOf and Migrator don't
+// currently need version-specific behavior and don't reference these fields.
+func (dia Dialector) detectServerVersion(db *gorm.DB) error {
+	if err := db.ConnPool.QueryRowContext(context.Background(), "SELECT version()").Scan(&dia.Config.ServerVersion); err != nil {
+		return err
+	}
+
+	if result := serverVersionMatcher.FindStringSubmatch(dia.Config.ServerVersion); len(result) > 3 {
+		dia.Config.IsOpenGauss = result[1] == "openGauss"
+		dia.Config.ServerMajorVersion, _ = strconv.Atoi(result[2])
+		dia.Config.ServerMinorVersion, _ = strconv.Atoi(result[3])
+	}
+
+	return nil
+}
+
+// supportsReturning reports whether INSERT/UPDATE/DELETE ... RETURNING should be
+// appended to the registered callbacks. Real openGauss has always supported it;
+// a PostgreSQL-compat connection only gets it from 8.2 onward. Once the version
+// is unknown (SkipInitializeWithVersion) we fall back to the coarse config flag.
+func (dia Dialector) supportsReturning() bool {
+	if dia.WithoutReturning {
+		return false
+	}
+	if dia.Config.ServerVersion == "" {
+		return true
+	}
+	if dia.Config.IsOpenGauss {
+		return true
+	}
+	return dia.Config.ServerMajorVersion > 8 || (dia.Config.ServerMajorVersion == 8 && dia.Config.ServerMinorVersion >= 2)
+}
+
 func (dia Dialector) Migrator(db *gorm.DB) gorm.Migrator {
 	return Migrator{migrator.Migrator{Config: migrator.Config{
 		DB:                          db,
@@ -179,8 +328,18 @@ func (dia Dialector) QuoteTo(writer clause.Writer, str string) {
 
 var numericPlaceholder = regexp.MustCompile(`\$(\d+)`)
 
+// errMergeReturningUnsupported is returned when a statement combines
+// Config.UseMerge with a RETURNING clause: MERGE's RETURNING semantics
+// differ from a plain INSERT's and this Dialector has no compatibility path
+// for it yet, so the clause is rejected rather than silently dropped.
+var errMergeReturningUnsupported = errors.New("opengauss: RETURNING is not supported together with UseMerge")
+
 func (dia Dialector) Explain(sql string, vars ...interface{}) string {
-	return logger.ExplainSQL(sql, numericPlaceholder, `'`, vars...)
+	redactedVars, ok := dia.redactVars(sql, vars)
+	if !ok {
+		return redactionFailedSQL
+	}
+	return logger.ExplainSQL(sql, numericPlaceholder, `'`, redactedVars...)
 }
 
 func (dia Dialector) DataTypeOf(field *schema.Field) string {
@@ -268,56 +427,33 @@ func (dia Dialector) RollbackTo(tx *gorm.DB, name string) error {
 }
 
 func (dia Dialector) ClauseBuilders() map[string]clause.ClauseBuilder {
-	clauseBuilders := map[string]clause.ClauseBuilder{
-		"ON CONFLICT": func(c clause.Clause, builder clause.Builder) {
-			onConflict, _ := c.Expression.(clause.OnConflict)
-			stmt := builder.(*gorm.Statement)
-			s := stmt.Schema
-
-			builder.WriteString("ON DUPLICATE KEY UPDATE ")
-
-			firstColumn := true
-			for idx, assignment := range onConflict.DoUpdates {
-				lookUpField := s.LookUpField(assignment.Column.Name)
-				tagSettings := lookUpField.TagSettings
-				_, isUniqueIndex := tagSettings["UNIQUEINDEX"]
-				// 'INSERT  ** ON DUPLICATE KEY UPDATE' don't allow update on primary key or unique key
-				if lookUpField.Unique || lookUpField.PrimaryKey || isUniqueIndex {
-					continue
-				}
-
-				if idx > 0 && !firstColumn {
-					builder.WriteByte(',')
-				}
-
-				builder.WriteQuoted(assignment.Column)
-				firstColumn = false
-				builder.WriteByte('=')
-				if column, ok := assignment.Value.(clause.Column); ok && column.Table == "excluded" {
-					builder.WriteQuoted(column)
-				} else {
-					builder.AddVar(builder, assignment.Value)
-				}
-			}
-
-			// add NOTHING
-			if len(onConflict.DoUpdates) == 0 || onConflict.DoNothing == true || firstColumn {
-				if s != nil {
-					builder.WriteString("NOTHING ")
-				}
-			}
+	var onConflictBuilder clause.ClauseBuilder
+	switch {
+	case dia.UseMerge:
+		// MERGE rewrites the whole INSERT ... VALUES statement, so it takes
+		// priority over the two plain upsert rewrites below.
+		onConflictBuilder = dia.mergeBuilder
+	case dia.IsOpenGauss:
+		onConflictBuilder = dia.onDuplicateKeyUpdateBuilder
+	default:
+		// Plain PostgreSQL (or a version that doesn't understand openGauss's
+		// MySQL-flavoured upsert syntax) only understands the standard
+		// ON CONFLICT ... DO UPDATE SET form.
+		onConflictBuilder = dia.onConflictDoUpdateBuilder
+	}
 
-			// where condition
-			if len(onConflict.TargetWhere.Exprs) > 0 {
-				builder.WriteString(" WHERE ")
-				onConflict.TargetWhere.Build(builder)
-				builder.WriteByte(' ')
-			}
-		},
+	clauseBuilders := map[string]clause.ClauseBuilder{
+		"ON CONFLICT": onConflictBuilder,
 		"RETURNING": func(c clause.Clause, builder clause.Builder) {
-			// exist bath 'RETURNING' and 'ON CONFLICT', 'RETURNING' clauses is invalid
+			// exist bath 'RETURNING' and 'ON CONFLICT', 'RETURNING' clauses is invalid.
 			_, hasOnConflict := builder.(*gorm.Statement).Clauses["ON CONFLICT"]
 			if hasOnConflict {
+				// MERGE rewrites "ON CONFLICT" wholesale and has no RETURNING
+				// compatibility path implemented yet: fail loudly instead of
+				// silently dropping the caller's RETURNING clause.
+				if dia.UseMerge {
+					builder.(*gorm.Statement).DB.AddError(errMergeReturningUnsupported)
+				}
 				return
 			}
 
@@ -341,6 +477,200 @@ func (dia Dialector) ClauseBuilders() map[string]clause.ClauseBuilder {
 	return clauseBuilders
 }
 
+// onDuplicateKeyUpdateBuilder emits openGauss's MySQL-compatible
+// `ON DUPLICATE KEY UPDATE` upsert syntax.
+func (dia Dialector) onDuplicateKeyUpdateBuilder(c clause.Clause, builder clause.Builder) {
+	onConflict, _ := c.Expression.(clause.OnConflict)
+	stmt := builder.(*gorm.Statement)
+	s := stmt.Schema
+
+	builder.WriteString("ON DUPLICATE KEY UPDATE ")
+
+	firstColumn := true
+	for idx, assignment := range onConflict.DoUpdates {
+		lookUpField := s.LookUpField(assignment.Column.Name)
+		tagSettings := lookUpField.TagSettings
+		_, isUniqueIndex := tagSettings["UNIQUEINDEX"]
+		// 'INSERT  ** ON DUPLICATE KEY UPDATE' don't allow update on primary key or unique key
+		if lookUpField.Unique || lookUpField.PrimaryKey || isUniqueIndex {
+			continue
+		}
+
+		if idx > 0 && !firstColumn {
+			builder.WriteByte(',')
+		}
+
+		builder.WriteQuoted(assignment.Column)
+		firstColumn = false
+		builder.WriteByte('=')
+		if column, ok := assignment.Value.(clause.Column); ok && column.Table == "excluded" {
+			builder.WriteQuoted(column)
+		} else {
+			builder.AddVar(builder, assignment.Value)
+		}
+	}
+
+	// add NOTHING
+	if len(onConflict.DoUpdates) == 0 || onConflict.DoNothing == true || firstColumn {
+		if s != nil {
+			builder.WriteString("NOTHING ")
+		}
+	}
+
+	// where condition
+	if len(onConflict.TargetWhere.Exprs) > 0 {
+		builder.WriteString(" WHERE ")
+		onConflict.TargetWhere.Build(builder)
+		builder.WriteByte(' ')
+	}
+}
+
+// onConflictDoUpdateBuilder emits the standard PostgreSQL
+// `ON CONFLICT (...) DO UPDATE SET ...` / `DO NOTHING` upsert syntax, for use
+// against plain PostgreSQL or a PostgreSQL-compat connection.
+func (dia Dialector) onConflictDoUpdateBuilder(c clause.Clause, builder clause.Builder) {
+	onConflict, _ := c.Expression.(clause.OnConflict)
+
+	// Registering this in db.ClauseBuilders["ON CONFLICT"] replaces gorm's
+	// default clause.Clause.Build entirely, including the "ON CONFLICT "
+	// keyword it would otherwise have written, so it must be written here.
+	builder.WriteString("ON CONFLICT ")
+
+	if onConflict.OnConstraint != "" {
+		builder.WriteString("ON CONSTRAINT ")
+		builder.WriteString(onConflict.OnConstraint)
+	} else if len(onConflict.Columns) > 0 {
+		builder.WriteByte('(')
+		for idx, column := range onConflict.Columns {
+			if idx > 0 {
+				builder.WriteByte(',')
+			}
+			builder.WriteQuoted(column)
+		}
+		builder.WriteByte(')')
+	}
+
+	if len(onConflict.TargetWhere.Exprs) > 0 {
+		builder.WriteString(" WHERE ")
+		onConflict.TargetWhere.Build(builder)
+	}
+
+	if onConflict.DoNothing || len(onConflict.DoUpdates) == 0 {
+		builder.WriteString(" DO NOTHING")
+		return
+	}
+
+	builder.WriteString(" DO UPDATE SET ")
+	for idx, assignment := range onConflict.DoUpdates {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+
+		builder.WriteQuoted(assignment.Column)
+		builder.WriteByte('=')
+		if column, ok := assignment.Value.(clause.Column); ok && column.Table == "excluded" {
+			builder.WriteQuoted(column)
+		} else {
+			builder.AddVar(builder, assignment.Value)
+		}
+	}
+}
+
+// mergeBuilder rewrites the whole "INSERT INTO ... VALUES ..." statement
+// already written by the INSERT and VALUES clause builders into a
+// `MERGE INTO target USING (VALUES ...) AS excluded ON <conflict-target>
+// WHEN MATCHED THEN UPDATE SET ... WHEN NOT MATCHED THEN INSERT ...`
+// statement, the only upsert form that can update a column which is itself
+// part of the conflict target and that supports composite conflict targets.
+func (dia Dialector) mergeBuilder(c clause.Clause, builder clause.Builder) {
+	stmt := builder.(*gorm.Statement)
+	onConflict, _ := c.Expression.(clause.OnConflict)
+	insertClause, _ := stmt.Clauses["INSERT"].Expression.(clause.Insert)
+	valuesClause, _ := stmt.Clauses["VALUES"].Expression.(clause.Values)
+
+	table := insertClause.Table
+	if table.Name == "" {
+		table = clause.Table{Name: stmt.Table}
+	}
+
+	targetColumns := onConflict.Columns
+	if len(targetColumns) == 0 && stmt.Schema != nil && stmt.Schema.PrioritizedPrimaryField != nil {
+		targetColumns = []clause.Column{{Name: stmt.Schema.PrioritizedPrimaryField.DBName}}
+	}
+
+	// Discard what INSERT/VALUES already wrote; MERGE replaces the statement
+	// wholesale rather than appending to it.
+	stmt.SQL.Reset()
+	stmt.Vars = stmt.Vars[:0]
+
+	builder.WriteString("MERGE INTO ")
+	builder.WriteQuoted(table)
+	builder.WriteString(" USING (VALUES ")
+	for idx, row := range valuesClause.Values {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteByte('(')
+		builder.AddVar(builder, row...)
+		builder.WriteByte(')')
+	}
+	builder.WriteString(") AS excluded (")
+	for idx, column := range valuesClause.Columns {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteQuoted(column)
+	}
+	builder.WriteString(") ON (")
+	for idx, column := range targetColumns {
+		if idx > 0 {
+			builder.WriteString(" AND ")
+		}
+		builder.WriteQuoted(clause.Column{Table: table.Name, Name: column.Name})
+		builder.WriteByte('=')
+		builder.WriteQuoted(clause.Column{Table: "excluded", Name: column.Name})
+	}
+	if len(onConflict.TargetWhere.Exprs) > 0 {
+		builder.WriteString(" AND (")
+		onConflict.TargetWhere.Build(builder)
+		builder.WriteByte(')')
+	}
+	builder.WriteByte(')')
+
+	if !onConflict.DoNothing && len(onConflict.DoUpdates) > 0 {
+		builder.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		for idx, assignment := range onConflict.DoUpdates {
+			if idx > 0 {
+				builder.WriteByte(',')
+			}
+
+			builder.WriteQuoted(assignment.Column)
+			builder.WriteByte('=')
+			if column, ok := assignment.Value.(clause.Column); ok && column.Table == "excluded" {
+				builder.WriteQuoted(column)
+			} else {
+				builder.AddVar(builder, assignment.Value)
+			}
+		}
+	}
+
+	builder.WriteString(" WHEN NOT MATCHED THEN INSERT (")
+	for idx, column := range valuesClause.Columns {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteQuoted(column)
+	}
+	builder.WriteString(") VALUES (")
+	for idx, column := range valuesClause.Columns {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteQuoted(clause.Column{Table: "excluded", Name: column.Name})
+	}
+	builder.WriteByte(')')
+}
+
 func getSerialDatabaseType(s string) (dbType string, ok bool) {
 	switch s {
 	case "smallserial":
@@ -0,0 +1,378 @@
+package opengauss
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"gitee.com/opengauss/openGauss-connector-go-pq"
+	"gorm.io/gorm"
+)
+
+// HostRole declares the role a HostConfig is expected to play in a
+// primary/standby cluster.
+type HostRole string
+
+const (
+	Primary HostRole = "primary"
+	Standby HostRole = "standby"
+)
+
+// HostConfig describes one node of a Config.Hosts primary/standby cluster.
+type HostConfig struct {
+	// Addr is the node's "host:port" address.
+	Addr string
+	// Role is the node's expected role. A periodic health check
+	// (SELECT pg_is_in_recovery()) reconfirms this and demotes/promotes the
+	// node in the resolver's routing table if the cluster has failed over.
+	Role HostRole
+	// Weight controls this node's share of standby read traffic under
+	// weighted round robin. Ignored for Role Primary. Treated as 1 if <= 0.
+	Weight int
+}
+
+// defaultHealthCheckInterval is used when Config.HealthCheckInterval is unset.
+const defaultHealthCheckInterval = 30 * time.Second
+
+// reconnectableCodes are pq.Error codes that mean the node is gone (shutting
+// down or unreachable), as opposed to a query-level error: on these a node
+// is marked unhealthy and the operation is transparently retried against
+// another one.
+var reconnectableCodes = map[pq.ErrorCode]bool{
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"08006": true, // connection_failure
+}
+
+var (
+	selectMatcher    = regexp.MustCompile(`(?is)^\s*SELECT\b`)
+	returningMatcher = regexp.MustCompile(`(?i)\bRETURNING\b`)
+)
+
+// isReadOnly reports whether query is a plain SELECT that doesn't also
+// mutate data via RETURNING, i.e. safe to route to a standby and to retry
+// against another node on a connection error.
+func isReadOnly(query string) bool {
+	return selectMatcher.MatchString(query) && !returningMatcher.MatchString(query)
+}
+
+// isReconnectable reports whether err signals the connection itself is gone,
+// as opposed to a statement-level failure that retrying elsewhere wouldn't fix.
+func isReconnectable(err error) bool {
+	var pqErr pq.Error
+	return errors.As(err, &pqErr) && reconnectableCodes[pqErr.Code]
+}
+
+// resolverNode is one HostConfig's live connection plus the liveness/role
+// bookkeeping the health checker maintains.
+type resolverNode struct {
+	cfg HostConfig
+	db  *sql.DB
+
+	mu      sync.RWMutex
+	healthy bool
+	role    HostRole // last-observed actual role; may drift from cfg.Role after a failover
+}
+
+func (n *resolverNode) currentRole() HostRole {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.role
+}
+
+func (n *resolverNode) isHealthy() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.healthy
+}
+
+func (n *resolverNode) markUnhealthy() {
+	n.mu.Lock()
+	n.healthy = false
+	n.mu.Unlock()
+}
+
+// checkHealth runs SELECT pg_is_in_recovery() and updates the node's
+// liveness and observed role from the result.
+func (n *resolverNode) checkHealth(ctx context.Context) {
+	var inRecovery bool
+	err := n.db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err != nil {
+		n.healthy = false
+		return
+	}
+	n.healthy = true
+	if inRecovery {
+		n.role = Standby
+	} else {
+		n.role = Primary
+	}
+}
+
+// resolverPool is a gorm.ConnPool that routes writes and RETURNING-bearing
+// statements to the primary node and plain reads to standbys (weighted round
+// robin), reconnecting to a healthy node when a query fails with a
+// reconnectable pq.Error.
+//
+// It is deliberately simpler than gorm's own dbresolver plugin: routing is
+// decided from the SQL text rather than from per-call resolver config, since
+// ConnPool sees only (ctx, query, args) and has no access to gorm's clause
+// building.
+type resolverPool struct {
+	nodes []*resolverNode
+	stop  chan struct{}
+}
+
+// newResolverPool dials every Config.Hosts entry and starts its health-check
+// loop. base supplies everything but Host/Port (TLS, auth, run-time params),
+// built the same way a single-host Dialector would build its *pq.Config.
+func newResolverPool(hosts []HostConfig, base *pq.Config, checkInterval time.Duration) (*resolverPool, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("opengauss: Config.Hosts must not be empty")
+	}
+	if checkInterval <= 0 {
+		checkInterval = defaultHealthCheckInterval
+	}
+
+	p := &resolverPool{stop: make(chan struct{})}
+	for _, hc := range hosts {
+		host, port, err := splitHostPort(hc.Addr)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg := base.Copy()
+		cfg.Host = host
+		cfg.Port = port
+
+		connector, err := pq.NewConnectorConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("opengauss: host %q: %w", hc.Addr, err)
+		}
+
+		p.nodes = append(p.nodes, &resolverNode{cfg: hc, db: sql.OpenDB(connector), healthy: true, role: hc.Role})
+	}
+
+	go p.healthLoop(checkInterval)
+	return p, nil
+}
+
+func splitHostPort(addr string) (string, uint16, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, fmt.Errorf("opengauss: invalid host %q: %w", addr, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("opengauss: invalid port in host %q: %w", addr, err)
+	}
+	return host, uint16(port), nil
+}
+
+func (p *resolverPool) healthLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			for _, n := range p.nodes {
+				n.checkHealth(ctx)
+			}
+			cancel()
+		}
+	}
+}
+
+// Close stops the health-check loop and closes every node's connection, so
+// callers that replace or tear down a *gorm.DB don't leak the background
+// goroutine.
+func (p *resolverPool) Close() error {
+	close(p.stop)
+	var firstErr error
+	for _, n := range p.nodes {
+		if err := n.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// primary returns a healthy node currently observed to be primary, or nil if
+// none is available.
+func (p *resolverPool) primary() *resolverNode {
+	for _, n := range p.nodes {
+		if n.isHealthy() && n.currentRole() == Primary {
+			return n
+		}
+	}
+	return nil
+}
+
+// standby picks a healthy standby by weighted round robin, falling back to
+// the primary (and then to any healthy node) if no standby is available.
+func (p *resolverPool) standby() *resolverNode {
+	var candidates []*resolverNode
+	totalWeight := 0
+	for _, n := range p.nodes {
+		if n.isHealthy() && n.currentRole() == Standby {
+			candidates = append(candidates, n)
+			totalWeight += weightOf(n.cfg.Weight)
+		}
+	}
+	if len(candidates) == 0 {
+		if pr := p.primary(); pr != nil {
+			return pr
+		}
+		return p.anyHealthy()
+	}
+
+	pick := rand.Intn(totalWeight)
+	for _, n := range candidates {
+		pick -= weightOf(n.cfg.Weight)
+		if pick < 0 {
+			return n
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+func (p *resolverPool) anyHealthy() *resolverNode {
+	for _, n := range p.nodes {
+		if n.isHealthy() {
+			return n
+		}
+	}
+	return nil
+}
+
+func weightOf(w int) int {
+	if w <= 0 {
+		return 1
+	}
+	return w
+}
+
+// route picks the node a query should run against: the primary for writes
+// and RETURNING-bearing statements, a standby for plain reads.
+func (p *resolverPool) route(query string) *resolverNode {
+	if isReadOnly(query) {
+		return p.standby()
+	}
+	return p.primary()
+}
+
+func (p *resolverPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	n := p.route(query)
+	if n == nil {
+		return nil, errNoHealthyNode
+	}
+	return n.db.PrepareContext(ctx, query)
+}
+
+func (p *resolverPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	n := p.primary()
+	if n == nil {
+		return nil, errNoHealthyNode
+	}
+	result, err := n.db.ExecContext(ctx, query, args...)
+	if isReconnectable(err) {
+		n.markUnhealthy()
+		if retry := p.primary(); retry != nil {
+			return retry.db.ExecContext(ctx, query, args...)
+		}
+	}
+	return result, err
+}
+
+func (p *resolverPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	n := p.route(query)
+	if n == nil {
+		return nil, errNoHealthyNode
+	}
+	rows, err := n.db.QueryContext(ctx, query, args...)
+	if isReconnectable(err) {
+		// The node is gone regardless of what kind of statement this was;
+		// mark it down so routing stops sending it traffic. Only retry the
+		// statement itself when it's read-only: a write may have reached the
+		// server before the connection dropped, so blindly re-running it
+		// here could execute it twice.
+		n.markUnhealthy()
+		if isReadOnly(query) {
+			if retry := p.route(query); retry != nil {
+				return retry.db.QueryContext(ctx, query, args...)
+			}
+		}
+	}
+	return rows, err
+}
+
+// QueryRowContext cannot retry on a reconnectable error: *sql.Row defers any
+// error until Scan is called, by which point this method has already
+// returned. Callers that need retry-on-failover for a single-row read should
+// use QueryContext instead.
+func (p *resolverPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	n := p.route(query)
+	if n == nil {
+		n = p.anyHealthy()
+	}
+	if n == nil {
+		return noHealthyNodeDB.QueryRowContext(ctx, query, args...)
+	}
+	return n.db.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx routes transactions to the primary node, since a transaction's
+// statements must all run against the same physical connection and only the
+// primary can be written to. Returns errNoHealthyNode if no primary is
+// currently healthy.
+func (p *resolverPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	n := p.primary()
+	if n == nil {
+		return nil, errNoHealthyNode
+	}
+	return n.db.BeginTx(ctx, opts)
+}
+
+var errNoHealthyNode = errors.New("opengauss: no healthy host available")
+
+// errConnector is a driver.Connector whose Connect always fails with
+// errNoHealthyNode. noHealthyNodeDB exists only so QueryRowContext can
+// synthesize a *sql.Row carrying errNoHealthyNode when no node is available:
+// *sql.Row has no exported constructor, so the only way to make Scan return
+// a chosen error is to source the Row from a *sql.DB whose connector always
+// fails with that error.
+type errConnector struct{}
+
+func (errConnector) Connect(context.Context) (driver.Conn, error) { return nil, errNoHealthyNode }
+func (errConnector) Driver() driver.Driver                        { return nil }
+
+var noHealthyNodeDB = sql.OpenDB(errConnector{})
+
+// newResolverConnPool builds the Config.Hosts-backed gorm.ConnPool used by
+// Initialize. base is the *pq.Config parsed from Config.DSN (for TLS, auth
+// and run-time params); only its Host/Port are overridden per node.
+func (dia Dialector) newResolverConnPool() (gorm.ConnPool, error) {
+	dsn, err := dia.buildDSN()
+	if err != nil {
+		return nil, err
+	}
+	base, err := pq.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return newResolverPool(dia.Config.Hosts, base, dia.Config.HealthCheckInterval)
+}
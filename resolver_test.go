@@ -0,0 +1,49 @@
+package opengauss
+
+import (
+	"context"
+	"testing"
+)
+
+func newTestPool(nodes ...*resolverNode) *resolverPool {
+	return &resolverPool{nodes: nodes, stop: make(chan struct{})}
+}
+
+func TestQueryRowContextNoHealthyNodeDoesNotPanic(t *testing.T) {
+	p := newTestPool(&resolverNode{cfg: HostConfig{Role: Primary}, healthy: false, role: Primary})
+
+	row := p.QueryRowContext(context.Background(), "SELECT 1")
+	if row == nil {
+		t.Fatal("QueryRowContext() = nil, want a non-nil *sql.Row even with no healthy node")
+	}
+
+	var dest int
+	if err := row.Scan(&dest); err != errNoHealthyNode {
+		t.Fatalf("row.Scan() error = %v, want errNoHealthyNode", err)
+	}
+}
+
+func TestBeginTxNoHealthyPrimary(t *testing.T) {
+	p := newTestPool(&resolverNode{cfg: HostConfig{Role: Standby}, healthy: true, role: Standby})
+
+	_, err := p.BeginTx(context.Background(), nil)
+	if err != errNoHealthyNode {
+		t.Fatalf("BeginTx() error = %v, want errNoHealthyNode when no primary is healthy", err)
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	cases := map[string]bool{
+		"SELECT * FROM users":                               true,
+		"  select id from users where id = $1":              true,
+		"INSERT INTO users (name) VALUES ($1)":              false,
+		"UPDATE users SET name = $1":                        false,
+		"INSERT INTO users (name) VALUES ($1) RETURNING id": false,
+		"SELECT * FROM users RETURNING id":                  false,
+	}
+	for query, want := range cases {
+		if got := isReadOnly(query); got != want {
+			t.Errorf("isReadOnly(%q) = %v, want %v", query, got, want)
+		}
+	}
+}